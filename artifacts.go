@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const defaultMaxArtifactBytes = 10 << 20 // 10 MiB
+
+var defaultMimeAllowlist = []string{
+	"image/png",
+	"image/jpeg",
+	"application/pdf",
+	"text/csv",
+	"application/json",
+	"text/plain",
+}
+
+// collectArtifacts scans outDir (the container's mounted out/ directory)
+// for files the script produced and encodes each as MCP content: text files
+// inline as TextContent, images as ImageContent, and other allowed binary
+// formats as an EmbeddedResource carrying a base64 blob. Files exceeding
+// maxBytes or whose MIME type isn't in allowlist are skipped with a note
+// instead of being silently dropped, to avoid exfiltrating arbitrary blobs.
+func collectArtifacts(outDir string, maxBytes int64, allowlist []string) ([]mcp.Content, error) {
+	entries, err := os.ReadDir(outDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	allowed := make(map[string]bool, len(allowlist))
+	for _, m := range allowlist {
+		allowed[strings.TrimSpace(m)] = true
+	}
+
+	var content []mcp.Content
+	for _, name := range names {
+		fullPath := filepath.Join(outDir, name)
+
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			continue
+		}
+		if info.Size() > maxBytes {
+			content = append(content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("artifact %q skipped: %d bytes exceeds max_artifact_bytes (%d)", name, info.Size(), maxBytes),
+			})
+			continue
+		}
+
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			content = append(content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("artifact %q could not be read: %v", name, err),
+			})
+			continue
+		}
+
+		mimeType := mimeTypeFor(name, data)
+		if !allowed[mimeType] {
+			content = append(content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("artifact %q skipped: MIME type %q is not in mime_allowlist", name, mimeType),
+			})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(mimeType, "image/"):
+			content = append(content, mcp.ImageContent{
+				Type:     "image",
+				Data:     base64.StdEncoding.EncodeToString(data),
+				MIMEType: mimeType,
+			})
+		case strings.HasPrefix(mimeType, "text/") || mimeType == "application/json":
+			content = append(content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("artifact %s:\n%s", name, string(data)),
+			})
+		default:
+			content = append(content, mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.BlobResourceContents{
+					URI:      "artifact://" + name,
+					MIMEType: mimeType,
+					Blob:     base64.StdEncoding.EncodeToString(data),
+				},
+			})
+		}
+	}
+
+	return content, nil
+}
+
+// mimeTypeFor prefers the file extension (http.DetectContentType often can't
+// tell a CSV from plain text) and falls back to content sniffing.
+func mimeTypeFor(name string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".pdf":
+		return "application/pdf"
+	case ".csv":
+		return "text/csv"
+	case ".json":
+		return "application/json"
+	case ".txt":
+		return "text/plain"
+	}
+
+	detected := http.DetectContentType(data)
+	if idx := strings.Index(detected, ";"); idx != -1 {
+		detected = detected[:idx]
+	}
+	return detected
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMimeTypeFor(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"plot.png", []byte("not actually a png"), "image/png"},
+		{"photo.jpg", nil, "image/jpeg"},
+		{"photo.jpeg", nil, "image/jpeg"},
+		{"report.pdf", nil, "application/pdf"},
+		{"data.csv", nil, "text/csv"},
+		{"data.json", nil, "application/json"},
+		{"notes.txt", nil, "text/plain"},
+		{"noext", []byte("hello world"), "text/plain"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mimeTypeFor(tc.name, tc.data); got != tc.want {
+				t.Errorf("mimeTypeFor(%q) = %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	t.Run("missing out dir yields no error", func(t *testing.T) {
+		content, err := collectArtifacts(filepath.Join(t.TempDir(), "missing"), defaultMaxArtifactBytes, defaultMimeAllowlist)
+		if err != nil {
+			t.Fatalf("collectArtifacts: %v", err)
+		}
+		if content != nil {
+			t.Errorf("content = %v, want nil", content)
+		}
+	})
+
+	t.Run("text file is returned inline", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "out.txt"), []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := collectArtifacts(dir, defaultMaxArtifactBytes, defaultMimeAllowlist)
+		if err != nil {
+			t.Fatalf("collectArtifacts: %v", err)
+		}
+		if len(content) != 1 {
+			t.Fatalf("len(content) = %d, want 1", len(content))
+		}
+		text, ok := content[0].(mcp.TextContent)
+		if !ok || !strings.Contains(text.Text, "hello") {
+			t.Errorf("content[0] = %#v, want TextContent containing %q", content[0], "hello")
+		}
+	})
+
+	t.Run("file over max_artifact_bytes is skipped with a note", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := collectArtifacts(dir, 5, defaultMimeAllowlist)
+		if err != nil {
+			t.Fatalf("collectArtifacts: %v", err)
+		}
+		if len(content) != 1 {
+			t.Fatalf("len(content) = %d, want 1", len(content))
+		}
+		text, ok := content[0].(mcp.TextContent)
+		if !ok || !strings.Contains(text.Text, "exceeds max_artifact_bytes") {
+			t.Errorf("content[0] = %#v, want a size-skip notice", content[0])
+		}
+	})
+
+	t.Run("MIME type outside the allowlist is skipped with a note", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "out.csv"), []byte("a,b\n1,2"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := collectArtifacts(dir, defaultMaxArtifactBytes, []string{"image/png"})
+		if err != nil {
+			t.Fatalf("collectArtifacts: %v", err)
+		}
+		if len(content) != 1 {
+			t.Fatalf("len(content) = %d, want 1", len(content))
+		}
+		text, ok := content[0].(mcp.TextContent)
+		if !ok || !strings.Contains(text.Text, "not in mime_allowlist") {
+			t.Errorf("content[0] = %#v, want an allowlist-skip notice", content[0])
+		}
+	})
+
+	t.Run("files are returned in sorted name order", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"b.txt", "a.txt"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		content, err := collectArtifacts(dir, defaultMaxArtifactBytes, defaultMimeAllowlist)
+		if err != nil {
+			t.Fatalf("collectArtifacts: %v", err)
+		}
+		if len(content) != 2 {
+			t.Fatalf("len(content) = %d, want 2", len(content))
+		}
+		first, ok := content[0].(mcp.TextContent)
+		if !ok || !strings.Contains(first.Text, "a.txt") {
+			t.Errorf("content[0] = %#v, want the a.txt artifact first", content[0])
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// resolvedDeps is the sorted, whitespace-trimmed list of pinned requirements
+// for one call (duplicate lines are kept), identified by a stable hash so repeat calls with the same set can reuse a
+// cached virtualenv instead of reinstalling every time.
+type resolvedDeps struct {
+	requirementsTxt string
+	hash            string
+}
+
+// resolveDeps merges the comma-separated modules list and/or full
+// requirements.txt contents into one requirements.txt body and hashes it.
+func resolveDeps(modules []string, requirements string) resolvedDeps {
+	var lines []string
+	if requirements != "" {
+		lines = append(lines, strings.Split(strings.TrimSpace(requirements), "\n")...)
+	}
+	lines = append(lines, modules...)
+
+	trimmed := make([]string, 0, len(lines))
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			trimmed = append(trimmed, l)
+		}
+	}
+	sort.Strings(trimmed)
+
+	body := strings.Join(trimmed, "\n")
+	sum := sha256.Sum256([]byte(body))
+	return resolvedDeps{
+		requirementsTxt: body,
+		hash:            hex.EncodeToString(sum[:])[:16],
+	}
+}
+
+// cacheVolumeName returns a stable volume name for deps, so repeat calls
+// with the same resolved requirement set reuse the same installed venv.
+func cacheVolumeName(deps resolvedDeps) string {
+	return "python-mcp-venv-" + deps.hash
+}
+
+const pipOrUvInstall = `if command -v uv >/dev/null 2>&1; then uv pip install --quiet -r requirements.txt; else python -m pip install --quiet -r requirements.txt; fi`
+
+// prepareShellArgs returns the "sh -c" command that installs deps into the
+// venv cached at /opt/venv (a volume the caller is expected to have mounted
+// there), unless the cache is already warm and refresh is not set. It's meant to
+// be run via Runtime.Prepare with network access, independent of whatever
+// network setting the execution sandbox itself uses, since pip/uv need to
+// reach PyPI even when the script they're installing for runs with
+// --network=none. The install is wrapped in a flock on /opt/venv/.lock so
+// two concurrent calls racing on the same cold cache don't both run
+// `python -m venv` into the same volume at once.
+func prepareShellArgs(deps resolvedDeps, refresh bool) []string {
+	refreshFlag := "0"
+	if refresh {
+		refreshFlag = "1"
+	}
+
+	script := fmt.Sprintf(
+		`mkdir -p /opt/venv && exec 9>/opt/venv/.lock && flock 9 sh -c '`+
+			`VENV=/opt/venv; REFRESH=%s; `+
+			`if [ "$REFRESH" = "1" ] || [ ! -f "$VENV/.ready" ]; then `+
+			`rm -f "$VENV/.ready" && python -m venv --system-site-packages "$VENV" && . "$VENV/bin/activate" && %s && touch "$VENV/.ready"; `+
+			`fi'`,
+		refreshFlag, pipOrUvInstall,
+	)
+
+	return []string{"sh", "-c", script}
+}
+
+// sessionReplArgs returns the command that starts an interactive REPL for a
+// persistent session. When cacheable, deps were already installed by
+// prepareShellArgs into /opt/venv, so this only has to activate that venv
+// before handing off to the interpreter. Otherwise (e.g. HostRunner) it
+// installs and starts the REPL in one step.
+func sessionReplArgs(deps resolvedDeps, cacheable bool) []string {
+	switch {
+	case deps.requirementsTxt == "":
+		return []string{"python", "-u", "-i"}
+	case cacheable:
+		return []string{"sh", "-c", ". /opt/venv/bin/activate && exec python -u -i"}
+	default:
+		return []string{"sh", "-c", fmt.Sprintf("%s && exec python -u -i", pipOrUvInstall)}
+	}
+}
+
+// runShellArgs returns the "sh -c" command that runs script.py. When
+// cacheable is true, deps were already installed by prepareShellArgs into
+// /opt/venv, so this only has to activate that venv. Otherwise (e.g.
+// HostRunner, which can't share a mount across calls) it installs and runs
+// in one step.
+func runShellArgs(deps resolvedDeps, cacheable bool) []string {
+	switch {
+	case deps.requirementsTxt == "":
+		return []string{"python", "script.py"}
+	case cacheable:
+		return []string{"sh", "-c", ". /opt/venv/bin/activate && python script.py"}
+	default:
+		return []string{"sh", "-c", fmt.Sprintf("%s && python script.py", pipOrUvInstall)}
+	}
+}
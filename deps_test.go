@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveDeps(t *testing.T) {
+	t.Run("merges modules and requirements in sorted order, keeping duplicates", func(t *testing.T) {
+		deps := resolveDeps([]string{"requests", "numpy"}, "pandas==2.2.0\n\nrequests")
+		want := "numpy\npandas==2.2.0\nrequests\nrequests"
+		if deps.requirementsTxt != want {
+			t.Errorf("requirementsTxt = %q, want %q", deps.requirementsTxt, want)
+		}
+	})
+
+	t.Run("blank lines and whitespace are trimmed", func(t *testing.T) {
+		deps := resolveDeps(nil, "  requests  \n\n  \n")
+		if deps.requirementsTxt != "requests" {
+			t.Errorf("requirementsTxt = %q, want %q", deps.requirementsTxt, "requests")
+		}
+	})
+
+	t.Run("empty input yields empty requirementsTxt", func(t *testing.T) {
+		deps := resolveDeps(nil, "")
+		if deps.requirementsTxt != "" {
+			t.Errorf("requirementsTxt = %q, want empty", deps.requirementsTxt)
+		}
+	})
+
+	t.Run("hash is stable and order-independent", func(t *testing.T) {
+		a := resolveDeps([]string{"requests", "numpy"}, "")
+		b := resolveDeps([]string{"numpy", "requests"}, "")
+		if a.hash != b.hash {
+			t.Errorf("hash differs by input order: %q vs %q", a.hash, b.hash)
+		}
+	})
+
+	t.Run("hash changes with content", func(t *testing.T) {
+		a := resolveDeps([]string{"requests"}, "")
+		b := resolveDeps([]string{"numpy"}, "")
+		if a.hash == b.hash {
+			t.Errorf("hash %q same for different requirement sets", a.hash)
+		}
+	})
+}
+
+func TestCacheVolumeName(t *testing.T) {
+	deps := resolveDeps([]string{"requests"}, "")
+	name := cacheVolumeName(deps)
+	if !strings.HasPrefix(name, "python-mcp-venv-") {
+		t.Errorf("cacheVolumeName() = %q, want prefix %q", name, "python-mcp-venv-")
+	}
+	if !strings.HasSuffix(name, deps.hash) {
+		t.Errorf("cacheVolumeName() = %q, want suffix %q", name, deps.hash)
+	}
+}
+
+func TestPrepareShellArgs(t *testing.T) {
+	deps := resolveDeps([]string{"requests"}, "")
+
+	args := prepareShellArgs(deps, false)
+	if len(args) != 3 || args[0] != "sh" || args[1] != "-c" {
+		t.Fatalf("prepareShellArgs() = %v, want a 3-element sh -c command", args)
+	}
+	script := args[2]
+	if !strings.Contains(script, "flock 9") {
+		t.Errorf("script = %q, want a flock guard against concurrent installs", script)
+	}
+	if !strings.Contains(script, `REFRESH=0`) {
+		t.Errorf("script = %q, want REFRESH=0 when refresh is false", script)
+	}
+
+	refreshed := prepareShellArgs(deps, true)
+	if !strings.Contains(refreshed[2], "REFRESH=1") {
+		t.Errorf("script = %q, want REFRESH=1 when refresh is true", refreshed[2])
+	}
+}
+
+func TestRunShellArgs(t *testing.T) {
+	t.Run("no deps runs the script directly", func(t *testing.T) {
+		args := runShellArgs(resolvedDeps{}, true)
+		want := []string{"python", "script.py"}
+		if len(args) != len(want) || args[0] != want[0] || args[1] != want[1] {
+			t.Errorf("runShellArgs() = %v, want %v", args, want)
+		}
+	})
+
+	t.Run("cacheable deps activate the prepared venv", func(t *testing.T) {
+		deps := resolveDeps([]string{"requests"}, "")
+		args := runShellArgs(deps, true)
+		if len(args) != 3 || !strings.Contains(args[2], "/opt/venv/bin/activate") {
+			t.Errorf("runShellArgs() = %v, want it to activate /opt/venv", args)
+		}
+	})
+
+	t.Run("non-cacheable deps install inline", func(t *testing.T) {
+		deps := resolveDeps([]string{"requests"}, "")
+		args := runShellArgs(deps, false)
+		if len(args) != 3 || !strings.Contains(args[2], "pip install") {
+			t.Errorf("runShellArgs() = %v, want an inline pip install", args)
+		}
+	})
+}
+
+func TestSessionReplArgs(t *testing.T) {
+	t.Run("no deps starts the REPL directly", func(t *testing.T) {
+		args := sessionReplArgs(resolvedDeps{}, true)
+		want := []string{"python", "-u", "-i"}
+		if len(args) != len(want) || args[0] != want[0] || args[1] != want[1] || args[2] != want[2] {
+			t.Errorf("sessionReplArgs() = %v, want %v", args, want)
+		}
+	})
+
+	t.Run("cacheable deps activate the prepared venv before the REPL", func(t *testing.T) {
+		deps := resolveDeps([]string{"requests"}, "")
+		args := sessionReplArgs(deps, true)
+		if len(args) != 3 || !strings.Contains(args[2], "/opt/venv/bin/activate") || !strings.Contains(args[2], "python -u -i") {
+			t.Errorf("sessionReplArgs() = %v, want it to activate /opt/venv then start the REPL", args)
+		}
+	})
+
+	t.Run("non-cacheable deps install before the REPL", func(t *testing.T) {
+		deps := resolveDeps([]string{"requests"}, "")
+		args := sessionReplArgs(deps, false)
+		if len(args) != 3 || !strings.Contains(args[2], "pip install") || !strings.Contains(args[2], "python -u -i") {
+			t.Errorf("sessionReplArgs() = %v, want an inline pip install before the REPL", args)
+		}
+	})
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -17,8 +19,40 @@ import (
 func main() {
 	// Parse command line arguments
 	sseMode := flag.Bool("sse", false, "Enable SSE mode instead of stdio")
+	outputCapBytes := flag.Int64("output-cap-bytes", 1<<20, "maximum bytes captured per stdout/stderr stream before truncating")
+	sessionIdleTimeout := flag.Duration("session-idle-timeout", 10*time.Minute, "how long an idle python-session-exec session is kept alive before it is stopped")
+	runtimeName := flag.String("runtime", "", "container runtime to use: podman, docker, nerdctl, or host (default: auto-detect podman/docker/nerdctl)")
+	defaultNetwork := flag.String("network", "none", "default container network mode: none or bridge")
+	defaultReadOnly := flag.Bool("readonly", true, "default to a read-only container root filesystem")
+	defaultUser := flag.String("user", "1000:1000", "default container user as uid:gid; empty runs as the image's default user")
+	defaultMemoryMB := flag.Int64("memory-mb", 512, "default memory limit in MB")
+	defaultCPUs := flag.Float64("cpus", 1.0, "default CPU limit")
+	defaultPIDsLimit := flag.Int("pids-limit", 128, "default limit on processes/threads inside the container")
+	defaultTimeoutSeconds := flag.Int("timeout-seconds", 30, "default execution timeout in seconds")
 	flag.Parse()
 
+	runtime, err := DetectRuntime(*runtimeName)
+	if err != nil {
+		log.Fatalf("Failed to select container runtime: %v", err)
+	}
+	log.Printf("Using %s runtime", runtime.Name())
+
+	executor := &pythonExecutor{
+		outputCapBytes: *outputCapBytes,
+		sessions:       newSessionManager(*sessionIdleTimeout),
+		runtime:        runtime,
+		sandbox: sandboxDefaults{
+			network:        *defaultNetwork,
+			readOnly:       *defaultReadOnly,
+			user:           *defaultUser,
+			memoryMB:       *defaultMemoryMB,
+			cpus:           *defaultCPUs,
+			pidsLimit:      *defaultPIDsLimit,
+			timeoutSeconds: *defaultTimeoutSeconds,
+		},
+	}
+	defer executor.sessions.CloseAll()
+
 	// Create an MCP server with basic capabilities
 	mcpServer := server.NewMCPServer(
 		"python-executor",
@@ -26,8 +60,7 @@ func main() {
 	)
 
 	// Register the Python executor
-	pythonTool := mcp.NewTool(
-		"execute-python",
+	pythonToolOptions := []mcp.ToolOption{
 		mcp.WithDescription("Execute Python code in an isolated environment"),
 		mcp.WithString(
 			"code",
@@ -38,9 +71,63 @@ func main() {
 			"modules",
 			mcp.Description("Comma-separated list of modules to import"),
 		),
+		mcp.WithString(
+			"requirements",
+			mcp.Description("Full requirements.txt contents (with version pins) to install instead of, or in addition to, modules"),
+		),
+		mcp.WithBoolean(
+			"refresh_cache",
+			mcp.Description("Force reinstalling dependencies even if a cached venv for this requirement set exists"),
+		),
+		mcp.WithString(
+			"stdin",
+			mcp.Description("Text piped to the script's standard input"),
+		),
+		mcp.WithNumber(
+			"max_artifact_bytes",
+			mcp.Description("Maximum size of any single file collected from out/ (default 10 MiB)"),
+		),
+		mcp.WithString(
+			"mime_allowlist",
+			mcp.Description("Comma-separated MIME types allowed to be returned from out/ (default: png, jpeg, pdf, csv, json, plain text)"),
+		),
+	}
+	pythonToolOptions = append(pythonToolOptions, sandboxToolOptions()...)
+	pythonTool := mcp.NewTool("execute-python", pythonToolOptions...)
+
+	mcpServer.AddTool(pythonTool, executor.handlePythonExecution)
+
+	sessionExecTool := mcp.NewTool(
+		"python-session-exec",
+		mcp.WithDescription("Execute Python code in a persistent REPL session, preserving variables and imports across calls"),
+		mcp.WithString(
+			"session_id",
+			mcp.Description("Existing session id to reuse; omit to start a new session"),
+		),
+		mcp.WithString(
+			"code",
+			mcp.Description("Python code to execute in the session"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"modules",
+			mcp.Description("Comma-separated list of modules to install when starting a new session"),
+		),
+	)
+
+	mcpServer.AddTool(sessionExecTool, executor.handleSessionExec)
+
+	sessionCloseTool := mcp.NewTool(
+		"python-session-close",
+		mcp.WithDescription("Terminate a persistent Python session and its container"),
+		mcp.WithString(
+			"session_id",
+			mcp.Description("Session id to close"),
+			mcp.Required(),
+		),
 	)
 
-	mcpServer.AddTool(pythonTool, handlePythonExecution)
+	mcpServer.AddTool(sessionCloseTool, executor.handleSessionClose)
 
 	// Run server in appropriate mode
 	if *sseMode {
@@ -58,8 +145,55 @@ func main() {
 	}
 }
 
+// pythonImage is the container image used for every Python invocation,
+// one-shot or session-based.
+const pythonImage = "mcr.microsoft.com/playwright/python:v1.49.1-noble"
+
+// pythonExecutor holds the server-wide configuration shared by the tool handlers.
+type pythonExecutor struct {
+	outputCapBytes int64
+	sessions       *sessionManager
+	runtime        Runtime
+	sandbox        sandboxDefaults
+}
+
+// cappedBuffer is a bytes.Buffer that stops growing past limit bytes and
+// records that truncation happened instead of buffering without bound.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if c.truncated {
+		return len(p), nil
+	}
+
+	remaining := c.limit - int64(c.buf.Len())
+	if remaining <= 0 {
+		c.truncated = true
+		return len(p), nil
+	}
+
+	if int64(len(p)) > remaining {
+		c.buf.Write(p[:remaining])
+		c.truncated = true
+		return len(p), nil
+	}
+
+	return c.buf.Write(p)
+}
+
+func (c *cappedBuffer) String() string {
+	if c.truncated {
+		return c.buf.String() + fmt.Sprintf("\n... [output truncated at %d bytes]", c.limit)
+	}
+	return c.buf.String()
+}
+
 // Define the handler for the Python executor
-func handlePythonExecution(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (e *pythonExecutor) handlePythonExecution(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	code, ok := request.Params.Arguments["code"].(string)
 	if !ok {
 		return mcp.NewToolResultError("Invalid code parameter"), nil
@@ -70,6 +204,9 @@ func handlePythonExecution(ctx context.Context, request mcp.CallToolRequest) (*m
 	if modulesStr, ok := request.Params.Arguments["modules"].(string); ok && modulesStr != "" {
 		modules = strings.Split(modulesStr, ",")
 	}
+	requirements, _ := request.Params.Arguments["requirements"].(string)
+	refreshCache, _ := request.Params.Arguments["refresh_cache"].(bool)
+	deps := resolveDeps(modules, requirements)
 
 	tmpDir, err := os.MkdirTemp("", "python_repl")
 	if err != nil {
@@ -84,34 +221,83 @@ func handlePythonExecution(ctx context.Context, request mcp.CallToolRequest) (*m
 		), nil
 	}
 
-	cmdArgs := []string{
-		"run",
-		"--rm",
-		"-v",
-		fmt.Sprintf("%s:/app", tmpDir),
-		"mcr.microsoft.com/playwright/python:v1.49.1-noble",
+	outDir := path.Join(tmpDir, "out")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		return mcp.NewToolResultError(
+			fmt.Sprintf("Failed to create artifact directory: %v", err),
+		), nil
 	}
 
-	shArgs := []string{}
+	if deps.requirementsTxt != "" {
+		err = os.WriteFile(path.Join(tmpDir, "requirements.txt"), []byte(deps.requirementsTxt+"\n"), 0644)
+		if err != nil {
+			return mcp.NewToolResultError(
+				fmt.Sprintf("Failed to write requirements.txt: %v", err),
+			), nil
+		}
+	}
+
+	// Only container runtimes can share a writable cache volume across
+	// calls; HostRunner reinstalls every time.
+	_, cacheable := e.runtime.(*containerRuntime)
 
-	if len(modules) > 0 {
-		shArgs = append(shArgs, "python", "-m", "pip", "install", "--quiet")
-		shArgs = append(shArgs, modules...)
-		shArgs = append(shArgs, "&&")
+	mounts := []Mount{{Source: tmpDir, Target: "/app"}}
+	if cacheable && deps.requirementsTxt != "" {
+		mounts = append(mounts, Mount{Source: cacheVolumeName(deps), Target: "/opt/venv"})
+
+		// Dependency installs need to reach PyPI even when the execution
+		// sandbox itself runs with --network=none, so this runs as a
+		// separate Prepare step with its own network setting rather than
+		// inheriting the (possibly disconnected) execution network.
+		prepareCtx, cancelPrepare := context.WithTimeout(ctx, 5*time.Minute)
+		prepareErr := e.runtime.Prepare(prepareCtx, ExecSpec{
+			Image:          pythonImage,
+			Mounts:         mounts,
+			Args:           prepareShellArgs(deps, refreshCache),
+			WorkDir:        tmpDir,
+			OutputCapBytes: e.outputCapBytes,
+			Network:        "bridge",
+			Timeout:        5 * time.Minute,
+		})
+		cancelPrepare()
+		if prepareErr != nil {
+			return mcp.NewToolResultError(
+				fmt.Sprintf("Failed to install dependencies: %v", prepareErr),
+			), nil
+		}
 	}
 
-	shArgs = append(shArgs, "python", path.Join("app", "script.py"))
-	cmdArgs = append(cmdArgs, "sh", "-c", strings.Join(shArgs, " "))
+	spec := ExecSpec{
+		Image:          pythonImage,
+		Mounts:         mounts,
+		Args:           runShellArgs(deps, cacheable),
+		WorkDir:        tmpDir,
+		OutputCapBytes: e.outputCapBytes,
+	}
+
+	if stdin, ok := request.Params.Arguments["stdin"].(string); ok && stdin != "" {
+		spec.Stdin = strings.NewReader(stdin)
+	}
 
-	cmd := exec.Command("podman", cmdArgs...)
-	out, err := cmd.Output()
+	if reporter := newProgressReporter(ctx, request); reporter != nil {
+		spec.OnOutput = reporter.onOutput
+	}
+
+	timeout := e.sandbox.applyTo(&spec, request.Params.Arguments)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The runtime ties ctx's lifetime to both the local process and, for
+	// container runtimes, the container itself (via an explicit kill -- see
+	// containerRuntime.killOnCancel), so client-side cancellation or a
+	// timeout actually stops work instead of leaving it running in the
+	// background.
+	stdout, stderr, exitCode, err := e.runtime.RunCmd(runCtx, spec)
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) {
 			return mcp.NewToolResultError(
-				fmt.Sprintf("Python exited with code %d: %s",
-					exitError.ExitCode(),
-					string(exitError.Stderr),
-				),
+				fmt.Sprintf("%v; retry with a higher timeout_seconds if the script needs more time", timeoutErr),
 			), nil
 		}
 
@@ -120,6 +306,36 @@ func handlePythonExecution(ctx context.Context, request mcp.CallToolRequest) (*m
 		), nil
 	}
 
-	return mcp.NewToolResultText(string(out)), nil
+	content := []mcp.Content{
+		mcp.TextContent{Type: "text", Text: fmt.Sprintf("stdout:\n%s", stdout)},
+		mcp.TextContent{Type: "text", Text: fmt.Sprintf("stderr:\n%s", stderr)},
+	}
+
+	maxArtifactBytes := int64(defaultMaxArtifactBytes)
+	if v, ok := request.Params.Arguments["max_artifact_bytes"].(float64); ok && v > 0 {
+		maxArtifactBytes = int64(v)
+	}
+	mimeAllowlist := defaultMimeAllowlist
+	if v, ok := request.Params.Arguments["mime_allowlist"].(string); ok && v != "" {
+		mimeAllowlist = strings.Split(v, ",")
+	}
+
+	artifacts, err := collectArtifacts(outDir, maxArtifactBytes, mimeAllowlist)
+	if err != nil {
+		content = append(content, mcp.TextContent{Type: "text", Text: fmt.Sprintf("Failed to collect artifacts: %v", err)})
+	} else {
+		content = append(content, artifacts...)
+	}
+
+	if exitCode != 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: append(content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Python exited with code %d", exitCode),
+			}),
+		}, nil
+	}
 
+	return &mcp.CallToolResult{Content: content}, nil
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCappedBufferWrite(t *testing.T) {
+	t.Run("under limit", func(t *testing.T) {
+		var c cappedBuffer
+		c.limit = 10
+		if _, err := c.Write([]byte("hello")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if got := c.String(); got != "hello" {
+			t.Errorf("String() = %q, want %q", got, "hello")
+		}
+	})
+
+	t.Run("exact limit", func(t *testing.T) {
+		var c cappedBuffer
+		c.limit = 5
+		if _, err := c.Write([]byte("hello")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if got := c.String(); got != "hello" {
+			t.Errorf("String() = %q, want %q", got, "hello")
+		}
+		if c.truncated {
+			t.Error("truncated = true, want false for a write landing exactly on the limit")
+		}
+	})
+
+	t.Run("over limit truncates and marks truncated", func(t *testing.T) {
+		var c cappedBuffer
+		c.limit = 5
+		if _, err := c.Write([]byte("hello world")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if !c.truncated {
+			t.Error("truncated = false, want true")
+		}
+		if !strings.HasPrefix(c.String(), "hello") {
+			t.Errorf("String() = %q, want prefix %q", c.String(), "hello")
+		}
+		if !strings.Contains(c.String(), "truncated at 5 bytes") {
+			t.Errorf("String() = %q, want a truncation notice", c.String())
+		}
+	})
+
+	t.Run("writes after truncation are dropped without growing the buffer", func(t *testing.T) {
+		var c cappedBuffer
+		c.limit = 5
+		if _, err := c.Write([]byte("hello world")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		before := c.buf.Len()
+		if _, err := c.Write([]byte("more")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if c.buf.Len() != before {
+			t.Errorf("buf.Len() = %d after post-truncation write, want unchanged %d", c.buf.Len(), before)
+		}
+	})
+
+	t.Run("Write always reports the full length, even when capped", func(t *testing.T) {
+		var c cappedBuffer
+		c.limit = 2
+		n, err := c.Write([]byte("hello"))
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if n != 5 {
+			t.Errorf("Write() returned n = %d, want 5 (callers like io.Copy treat a short count as an error)", n)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressReporter streams the output of a long-running execution back to
+// the client as it's produced, via MCP progress notifications, instead of
+// making the client wait for the final CallToolResult. It is safe to call
+// from the concurrent stdout/stderr copy goroutines os/exec starts.
+type progressReporter struct {
+	ctx   context.Context
+	srv   *server.MCPServer
+	token mcp.ProgressToken
+
+	mu  sync.Mutex
+	seq int
+}
+
+// newProgressReporter returns nil if request didn't ask for progress
+// notifications (no progress token) or there's no server attached to ctx,
+// in which case callers should skip streaming entirely.
+func newProgressReporter(ctx context.Context, request mcp.CallToolRequest) *progressReporter {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil
+	}
+
+	return &progressReporter{ctx: ctx, srv: srv, token: request.Params.Meta.ProgressToken}
+}
+
+func (p *progressReporter) onOutput(stream, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+
+	// Send only this line's delta rather than the whole transcript so far:
+	// resending the accumulated output on every call would make total bytes
+	// sent over the wire grow quadratically with line count on a chatty
+	// script.
+	_ = p.srv.SendNotificationToClient(p.ctx, "notifications/progress", map[string]interface{}{
+		"progressToken": p.token,
+		"progress":      p.seq,
+		"output":        fmt.Sprintf("[%s] %s\n", stream, line),
+	})
+}
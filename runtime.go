@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mount describes a host directory bind-mounted into the execution
+// environment.
+type Mount struct {
+	Source string
+	Target string
+}
+
+// ExecSpec is the backend-agnostic description of a single Python
+// invocation. Each Runtime translates it into the CLI arguments or direct
+// exec call appropriate for that backend.
+type ExecSpec struct {
+	Image    string
+	Mounts   []Mount
+	Env      []string
+	Args     []string
+	Network  string
+	ReadOnly bool
+	User     string
+
+	MemoryMB  int64
+	CPUs      float64
+	PIDsLimit int
+
+	// WorkDir is the host directory containing the script; container
+	// runtimes set /app as the working directory when it is non-empty (the
+	// caller mounts WorkDir there via Mounts), while HostRunner runs Args
+	// directly from it.
+	WorkDir string
+
+	Stdin          io.Reader
+	OutputCapBytes int64
+
+	// Timeout is the budget ctx was given for this call; it is only used to
+	// build a clearer message when RunCmd is killed by ctx's deadline.
+	Timeout time.Duration
+
+	// OnOutput, when set, is called once per complete line of stdout or
+	// stderr ("stdout"/"stderr", line) as the process produces it, so
+	// callers can stream progress instead of waiting for RunCmd to return.
+	OnOutput func(stream string, line string)
+}
+
+// TimeoutError is returned by RunCmd when ctx's deadline killed the process,
+// so callers can tell a timeout apart from other failures and, e.g., retry
+// with a larger timeout_seconds.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("execution timed out after %s", e.Timeout)
+}
+
+// Runtime executes Python workloads on behalf of a tool handler, hiding the
+// differences between container engines (and the no-container host case)
+// behind one interface.
+type Runtime interface {
+	// Name identifies the backend, e.g. for error messages.
+	Name() string
+	// Prepare does any backend-specific setup (such as installing
+	// dependencies) before RunCmd or StartSession is called.
+	Prepare(ctx context.Context, spec ExecSpec) error
+	// RunCmd runs spec and returns the captured stdout/stderr and exit code.
+	RunCmd(ctx context.Context, spec ExecSpec) (stdout string, stderr string, exitCode int, err error)
+	// Cleanup releases any resources Prepare created.
+	Cleanup(ctx context.Context, spec ExecSpec) error
+	// StartSession launches spec as a long-lived interactive process (e.g.
+	// `python -i`) instead of running it to completion, so callers can send
+	// it multiple commands over time. Unlike RunCmd, the process outlives
+	// ctx; callers stop it explicitly via the returned Session.
+	StartSession(ctx context.Context, spec ExecSpec) (Session, error)
+}
+
+// Session is a long-lived interactive process started by Runtime.StartSession.
+type Session interface {
+	Stdin() io.WriteCloser
+	Stdout() io.Reader
+	// Stop terminates the session: the container (if any) and the process.
+	Stop(ctx context.Context) error
+}
+
+// containerRuntime implements Runtime for any docker-CLI-compatible engine
+// (podman, docker, nerdctl all accept the same "run" flags we need here).
+type containerRuntime struct {
+	binary string
+}
+
+func NewPodmanRuntime() Runtime  { return &containerRuntime{binary: "podman"} }
+func NewDockerRuntime() Runtime  { return &containerRuntime{binary: "docker"} }
+func NewNerdctlRuntime() Runtime { return &containerRuntime{binary: "nerdctl"} }
+
+func (c *containerRuntime) Name() string { return c.binary }
+
+// Prepare runs spec (expected to be an install-only command, see
+// prepareShellArgs) to completion before the caller's real RunCmd, so setup
+// steps like dependency installation can use different settings (e.g.
+// network access) than the sandboxed execution that follows.
+func (c *containerRuntime) Prepare(ctx context.Context, spec ExecSpec) error {
+	if len(spec.Args) == 0 {
+		return nil
+	}
+
+	cidPath, cleanupCidFile, err := newCidFile()
+	if err != nil {
+		return err
+	}
+	defer cleanupCidFile()
+
+	cmd := exec.CommandContext(ctx, c.binary, c.buildArgs(spec, cidPath)...)
+	stopWatch := c.killOnCancel(ctx, cidPath)
+	defer stopWatch()
+
+	stdout, stderr, exitCode, err := runCapped(ctx, cmd, spec)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("exited with code %d: %s%s", exitCode, stderr, stdout)
+	}
+	return nil
+}
+
+func (c *containerRuntime) Cleanup(ctx context.Context, spec ExecSpec) error { return nil }
+
+func (c *containerRuntime) buildArgs(spec ExecSpec, cidPath string) []string {
+	args := []string{"run", "--rm", "-i", "--cidfile", cidPath}
+
+	for _, m := range spec.Mounts {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", m.Source, m.Target))
+	}
+	for _, e := range spec.Env {
+		args = append(args, "-e", e)
+	}
+	if spec.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", spec.Network))
+	}
+	if spec.ReadOnly {
+		args = append(args, "--read-only", "--tmpfs", "/tmp")
+	}
+	if spec.User != "" {
+		args = append(args, "--user", spec.User)
+	}
+	if spec.MemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", spec.MemoryMB))
+	}
+	if spec.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(spec.CPUs, 'f', -1, 64))
+	}
+	if spec.PIDsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(spec.PIDsLimit))
+	}
+	if spec.WorkDir != "" {
+		args = append(args, "-w", "/app")
+	}
+	// Baseline hardening applied to every container run, regardless of spec.
+	args = append(args, "--cap-drop=ALL", "--security-opt=no-new-privileges")
+
+	args = append(args, spec.Image)
+	args = append(args, spec.Args...)
+	return args
+}
+
+func (c *containerRuntime) RunCmd(ctx context.Context, spec ExecSpec) (string, string, int, error) {
+	// podman/docker/nerdctl detach the container from their own CLI process
+	// (conmon/containerd keep it running); killing the CLI on cancellation
+	// does not stop the container. Capture its id via --cidfile so we can
+	// issue an explicit kill ourselves when ctx is done.
+	cidPath, cleanupCidFile, err := newCidFile()
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer cleanupCidFile()
+
+	cmd := exec.CommandContext(ctx, c.binary, c.buildArgs(spec, cidPath)...)
+
+	stopWatch := c.killOnCancel(ctx, cidPath)
+	defer stopWatch()
+
+	return runCapped(ctx, cmd, spec)
+}
+
+// killOnCancel watches ctx and, if it's canceled or times out before the
+// caller calls the returned stop func, kills the container recorded in
+// cidPath. This is what actually stops the container on client cancellation
+// or a timeout -- exec.CommandContext alone only kills the CLI process.
+func (c *containerRuntime) killOnCancel(ctx context.Context, cidPath string) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			id := readCidFile(cidPath)
+			if id == "" {
+				return
+			}
+			killCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = exec.CommandContext(killCtx, c.binary, "kill", id).Run()
+		case <-done:
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func newCidFile() (path string, cleanup func(), err error) {
+	f, err := os.CreateTemp("", "python-mcp-cid-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create cidfile: %w", err)
+	}
+	path = f.Name()
+	f.Close()
+	// podman/docker refuse to write to a --cidfile that already exists.
+	if err := os.Remove(path); err != nil {
+		return "", nil, fmt.Errorf("failed to prepare cidfile: %w", err)
+	}
+	return path, func() { os.Remove(path) }, nil
+}
+
+// readCidFile polls briefly for the container id, since ctx can be canceled
+// before the container runtime has finished writing --cidfile.
+func readCidFile(path string) string {
+	for i := 0; i < 20; i++ {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return ""
+}
+
+// StartSession launches spec as a long-lived container, so the same
+// sandbox hardening (network/readonly/memory/cpus/pids/user) applied to
+// one-shot RunCmd calls also applies to interactive sessions. Like RunCmd,
+// the container's id is captured via --cidfile so Stop can kill it
+// directly rather than relying on the CLI process exiting.
+func (c *containerRuntime) StartSession(ctx context.Context, spec ExecSpec) (Session, error) {
+	cidPath, cleanupCidFile, err := newCidFile()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(c.binary, c.buildArgs(spec, cidPath)...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cleanupCidFile()
+		return nil, fmt.Errorf("failed to open session stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanupCidFile()
+		return nil, fmt.Errorf("failed to open session stdout: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		cleanupCidFile()
+		return nil, fmt.Errorf("failed to start session container: %w", err)
+	}
+
+	return &containerSession{
+		runtime:       c,
+		cmd:           cmd,
+		stdin:         stdin,
+		stdout:        stdout,
+		cidPath:       cidPath,
+		cleanupCidDir: cleanupCidFile,
+	}, nil
+}
+
+type containerSession struct {
+	runtime       *containerRuntime
+	cmd           *exec.Cmd
+	stdin         io.WriteCloser
+	stdout        io.Reader
+	cidPath       string
+	cleanupCidDir func()
+}
+
+func (s *containerSession) Stdin() io.WriteCloser { return s.stdin }
+func (s *containerSession) Stdout() io.Reader     { return s.stdout }
+
+func (s *containerSession) Stop(ctx context.Context) error {
+	defer s.cleanupCidDir()
+
+	s.stdin.Close()
+
+	if id := readCidFile(s.cidPath); id != "" {
+		killCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		_ = exec.CommandContext(killCtx, s.runtime.binary, "kill", id).Run()
+	}
+
+	return s.cmd.Wait()
+}
+
+// HostRunner execs python directly on the host, with no container isolation.
+// It exists for trusted deployments (e.g. CI runners) where podman/docker
+// aren't available or aren't wanted.
+type HostRunner struct{}
+
+func (HostRunner) Name() string { return "host" }
+
+func (HostRunner) Prepare(ctx context.Context, spec ExecSpec) error { return nil }
+
+func (HostRunner) Cleanup(ctx context.Context, spec ExecSpec) error { return nil }
+
+func (HostRunner) RunCmd(ctx context.Context, spec ExecSpec) (string, string, int, error) {
+	if len(spec.Args) == 0 {
+		return "", "", -1, fmt.Errorf("host runtime: empty command")
+	}
+
+	cmd := exec.CommandContext(ctx, spec.Args[0], spec.Args[1:]...)
+	cmd.Dir = spec.WorkDir
+	cmd.Env = append(os.Environ(), spec.Env...)
+	return runCapped(ctx, cmd, spec)
+}
+
+func (HostRunner) StartSession(ctx context.Context, spec ExecSpec) (Session, error) {
+	if len(spec.Args) == 0 {
+		return nil, fmt.Errorf("host runtime: empty command")
+	}
+
+	cmd := exec.Command(spec.Args[0], spec.Args[1:]...)
+	cmd.Dir = spec.WorkDir
+	cmd.Env = append(os.Environ(), spec.Env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session stdout: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start session process: %w", err)
+	}
+
+	return &hostSession{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+type hostSession struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+}
+
+func (s *hostSession) Stdin() io.WriteCloser { return s.stdin }
+func (s *hostSession) Stdout() io.Reader     { return s.stdout }
+
+func (s *hostSession) Stop(ctx context.Context) error {
+	s.stdin.Close()
+	if s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	return s.cmd.Wait()
+}
+
+func runCapped(ctx context.Context, cmd *exec.Cmd, spec ExecSpec) (string, string, int, error) {
+	if spec.Stdin != nil {
+		cmd.Stdin = spec.Stdin
+	}
+
+	capBytes := spec.OutputCapBytes
+	if capBytes <= 0 {
+		capBytes = 1 << 20
+	}
+
+	stdout := &cappedBuffer{limit: capBytes}
+	stderr := &cappedBuffer{limit: capBytes}
+	cmd.Stdout = io.Writer(stdout)
+	cmd.Stderr = io.Writer(stderr)
+
+	if spec.OnOutput != nil {
+		cmd.Stdout = io.MultiWriter(stdout, &lineWriter{onLine: func(line string) { spec.OnOutput("stdout", line) }})
+		cmd.Stderr = io.MultiWriter(stderr, &lineWriter{onLine: func(line string) { spec.OnOutput("stderr", line) }})
+	}
+
+	runErr := cmd.Run()
+	if runErr != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return stdout.String(), stderr.String(), -1, &TimeoutError{Timeout: spec.Timeout}
+		}
+		if exitErr, ok := runErr.(*exec.ExitError); ok {
+			return stdout.String(), stderr.String(), exitErr.ExitCode(), nil
+		}
+		return stdout.String(), stderr.String(), -1, runErr
+	}
+
+	return stdout.String(), stderr.String(), 0, nil
+}
+
+// lineWriter buffers partial writes and invokes onLine once per complete
+// line, so OnOutput callbacks see whole lines regardless of how the
+// underlying pipe happened to chunk them.
+type lineWriter struct {
+	buf    []byte
+	onLine func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		w.onLine(string(w.buf[:idx]))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// DetectRuntime resolves name to a Runtime. An empty name probes podman,
+// docker, then nerdctl in turn; "host" must always be requested explicitly
+// since it has no sandboxing.
+func DetectRuntime(name string) (Runtime, error) {
+	switch name {
+	case "podman":
+		return NewPodmanRuntime(), nil
+	case "docker":
+		return NewDockerRuntime(), nil
+	case "nerdctl":
+		return NewNerdctlRuntime(), nil
+	case "host":
+		return HostRunner{}, nil
+	case "":
+		candidates := []struct {
+			binary string
+			new    func() Runtime
+		}{
+			{"podman", NewPodmanRuntime},
+			{"docker", NewDockerRuntime},
+			{"nerdctl", NewNerdctlRuntime},
+		}
+		for _, c := range candidates {
+			if _, err := exec.LookPath(c.binary); err == nil {
+				return c.new(), nil
+			}
+		}
+		return nil, fmt.Errorf("no supported container runtime (podman, docker, nerdctl) found on PATH; pass -runtime=host to run python directly instead")
+	default:
+		return nil, fmt.Errorf("unknown runtime %q: want one of podman, docker, nerdctl, host", name)
+	}
+}
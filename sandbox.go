@@ -0,0 +1,93 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// sandboxDefaults holds the server-wide hardening defaults applied to every
+// execute-python call unless a tool argument explicitly relaxes them.
+type sandboxDefaults struct {
+	network        string
+	readOnly       bool
+	user           string
+	memoryMB       int64
+	cpus           float64
+	pidsLimit      int
+	timeoutSeconds int
+}
+
+// applyTo builds an ExecSpec's sandbox fields from d, letting any argument
+// present in args override the corresponding default.
+func (d sandboxDefaults) applyTo(spec *ExecSpec, args map[string]interface{}) time.Duration {
+	spec.Network = d.network
+	spec.ReadOnly = d.readOnly
+	spec.User = d.user
+	spec.MemoryMB = d.memoryMB
+	spec.CPUs = d.cpus
+	spec.PIDsLimit = d.pidsLimit
+
+	if v, ok := args["network"].(string); ok && v != "" {
+		spec.Network = v
+	}
+	if v, ok := args["readonly"].(bool); ok {
+		spec.ReadOnly = v
+	}
+	if v, ok := args["memory_mb"].(float64); ok && v > 0 {
+		spec.MemoryMB = int64(v)
+	}
+	if v, ok := args["cpus"].(float64); ok && v > 0 {
+		spec.CPUs = v
+	}
+	if v, ok := args["pids_limit"].(float64); ok && v > 0 {
+		spec.PIDsLimit = int(v)
+	}
+
+	timeout := d.timeoutFor(args)
+	spec.Timeout = timeout
+	return timeout
+}
+
+// timeoutFor resolves the execution timeout from args, falling back to d's
+// default. Shared by applyTo and callers (like the session handlers) that
+// need just the timeout without building a whole ExecSpec.
+func (d sandboxDefaults) timeoutFor(args map[string]interface{}) time.Duration {
+	timeoutSeconds := d.timeoutSeconds
+	if v, ok := args["timeout_seconds"].(float64); ok && v > 0 {
+		timeoutSeconds = int(v)
+	}
+	return time.Duration(timeoutSeconds) * time.Second
+}
+
+// sandboxToolOptions returns the mcp.ToolOption set for the sandbox
+// hardening knobs that execute-python exposes; python-session-exec does not
+// expose them.
+func sandboxToolOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString(
+			"network",
+			mcp.Description("Container network mode: \"none\" (default) or \"bridge\""),
+		),
+		mcp.WithBoolean(
+			"readonly",
+			mcp.Description("Run with a read-only container root filesystem (default true)"),
+		),
+		mcp.WithNumber(
+			"memory_mb",
+			mcp.Description("Memory limit in MB (default 512)"),
+		),
+		mcp.WithNumber(
+			"cpus",
+			mcp.Description("CPU limit, e.g. 1.5 (default 1)"),
+		),
+		mcp.WithNumber(
+			"pids_limit",
+			mcp.Description("Maximum number of processes/threads in the container (default 128)"),
+		),
+		mcp.WithNumber(
+			"timeout_seconds",
+			mcp.Description("Kill the process if it runs longer than this many seconds (default 30)"),
+		),
+	}
+}
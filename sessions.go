@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pySession is a single long-lived Runtime.Session running Python in
+// interactive mode, so that variables and imports persist across calls. It
+// gets the same sandbox hardening (network/readonly/memory/cpus/pids/user)
+// as execute-python, via the ExecSpec sessionManager.start builds.
+type pySession struct {
+	id      string
+	session Session
+	stdout  *bufio.Reader
+
+	mu       sync.Mutex
+	lastUsed time.Time
+	// broken is set once a run times out, since its reader goroutine is
+	// left running on stdout after run returns; a later run call must not
+	// start a second goroutine reading the same stdout concurrently with
+	// the orphaned one.
+	broken bool
+}
+
+// sessionManager tracks running sessions and garbage collects ones that have
+// been idle for longer than idleTimeout.
+type sessionManager struct {
+	mu          sync.Mutex
+	sessions    map[string]*pySession
+	idleTimeout time.Duration
+	stopGC      chan struct{}
+}
+
+func newSessionManager(idleTimeout time.Duration) *sessionManager {
+	m := &sessionManager{
+		sessions:    make(map[string]*pySession),
+		idleTimeout: idleTimeout,
+		stopGC:      make(chan struct{}),
+	}
+	go m.gcLoop()
+	return m
+}
+
+func (m *sessionManager) gcLoop() {
+	ticker := time.NewTicker(m.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.reapIdle()
+		case <-m.stopGC:
+			return
+		}
+	}
+}
+
+func (m *sessionManager) reapIdle() {
+	m.mu.Lock()
+	var expired []*pySession
+	for id, s := range m.sessions {
+		s.mu.Lock()
+		idle := time.Since(s.lastUsed) > m.idleTimeout
+		s.mu.Unlock()
+		if idle {
+			expired = append(expired, s)
+			delete(m.sessions, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, s := range expired {
+		s.close()
+	}
+}
+
+// start launches a new session via runtime and registers it under a fresh
+// id. The REPL itself is sandboxed the same way as a one-shot
+// execute-python call, using sandbox's defaults (no per-call overrides,
+// since python-session-exec doesn't expose the hardening tool parameters).
+// Any requested modules are installed first via a separate Prepare step
+// with network access, the same way chunk0-6 solved this for
+// execute-python, since the hardened REPL spec itself runs with
+// --network=none/--read-only/non-root and can't install anything.
+func (m *sessionManager) start(ctx context.Context, runtime Runtime, sandbox sandboxDefaults, modules []string) (*pySession, error) {
+	deps := resolveDeps(modules, "")
+
+	// Only container runtimes can share a writable cache volume across
+	// calls; HostRunner installs and starts the REPL in one step.
+	_, cacheable := runtime.(*containerRuntime)
+
+	var mounts []Mount
+	if cacheable && deps.requirementsTxt != "" {
+		mounts = append(mounts, Mount{Source: cacheVolumeName(deps), Target: "/opt/venv"})
+
+		prepareCtx, cancelPrepare := context.WithTimeout(ctx, 5*time.Minute)
+		prepareErr := runtime.Prepare(prepareCtx, ExecSpec{
+			Image:   pythonImage,
+			Mounts:  mounts,
+			Args:    prepareShellArgs(deps, false),
+			Network: "bridge",
+			Timeout: 5 * time.Minute,
+		})
+		cancelPrepare()
+		if prepareErr != nil {
+			return nil, fmt.Errorf("failed to install session dependencies: %w", prepareErr)
+		}
+	}
+
+	spec := ExecSpec{
+		Image:  pythonImage,
+		Mounts: mounts,
+		Args:   sessionReplArgs(deps, cacheable),
+	}
+	sandbox.applyTo(&spec, nil)
+
+	session, err := runtime.StartSession(ctx, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	s := &pySession{
+		id:       uuid.New().String(),
+		session:  session,
+		stdout:   bufio.NewReader(session.Stdout()),
+		lastUsed: time.Now(),
+	}
+
+	m.mu.Lock()
+	m.sessions[s.id] = s
+	m.mu.Unlock()
+
+	return s, nil
+}
+
+func (m *sessionManager) get(id string) (*pySession, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	return s, ok
+}
+
+func (m *sessionManager) close(id string) bool {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if ok {
+		delete(m.sessions, id)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	s.close()
+	return true
+}
+
+// CloseAll tears down every tracked session. Called on server shutdown.
+func (m *sessionManager) CloseAll() {
+	close(m.stopGC)
+
+	m.mu.Lock()
+	sessions := make([]*pySession, 0, len(m.sessions))
+	for id, s := range m.sessions {
+		sessions = append(sessions, s)
+		delete(m.sessions, id)
+	}
+	m.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+}
+
+func (s *pySession) close() {
+	stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = s.session.Stop(stopCtx)
+}
+
+// run sends code to the session's stdin and reads output up to a sentinel
+// line the session prints once the code has finished executing, or until
+// timeout elapses.
+func (s *pySession) run(ctx context.Context, code string, timeout time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.broken {
+		return "", fmt.Errorf("session is unusable after a previous timeout; close it and start a new one")
+	}
+
+	sentinel := fmt.Sprintf("__mcp_session_done_%s__", uuid.New().String())
+	stdin := s.session.Stdin()
+
+	if _, err := io.WriteString(stdin, code+"\n"); err != nil {
+		return "", fmt.Errorf("failed to write to session: %w", err)
+	}
+	// The interactive interpreter needs a blank line to close an indented
+	// compound statement (def/for/if/while/class); without it, code ending
+	// in an indented block leaves the REPL waiting for more input and the
+	// sentinel below is never seen as a statement of its own.
+	if _, err := io.WriteString(stdin, "\n"); err != nil {
+		return "", fmt.Errorf("failed to write to session: %w", err)
+	}
+	if _, err := io.WriteString(stdin, fmt.Sprintf("print(%q)\n", sentinel)); err != nil {
+		return "", fmt.Errorf("failed to write sentinel to session: %w", err)
+	}
+
+	type result struct {
+		out string
+		err error
+	}
+	resultCh := make(chan result, 1)
+
+	go func() {
+		var out strings.Builder
+		for {
+			line, err := s.stdout.ReadString('\n')
+			if strings.Contains(line, sentinel) {
+				resultCh <- result{out.String(), nil}
+				return
+			}
+			out.WriteString(line)
+			if err != nil {
+				resultCh <- result{out.String(), fmt.Errorf("session ended unexpectedly: %w", err)}
+				return
+			}
+		}
+	}()
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case r := <-resultCh:
+		s.lastUsed = time.Now()
+		return r.out, r.err
+	case <-runCtx.Done():
+		// The read goroutine above is left running and will be collected
+		// (and its output discarded) once the session eventually produces
+		// the sentinel or exits. Mark the session broken so a concurrent or
+		// later run call can't start a second goroutine reading the same
+		// stdout out from under it before the caller closes the session.
+		s.broken = true
+		return "", &TimeoutError{Timeout: timeout}
+	}
+}
+
+func (e *pythonExecutor) handleSessionExec(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	code, ok := request.Params.Arguments["code"].(string)
+	if !ok {
+		return mcp.NewToolResultError("Invalid code parameter"), nil
+	}
+
+	var session *pySession
+	if id, ok := request.Params.Arguments["session_id"].(string); ok && id != "" {
+		session, ok = e.sessions.get(id)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown session_id: %s", id)), nil
+		}
+	} else {
+		var modules []string
+		if modulesStr, ok := request.Params.Arguments["modules"].(string); ok && modulesStr != "" {
+			modules = strings.Split(modulesStr, ",")
+		}
+
+		started, err := e.sessions.start(ctx, e.runtime, e.sandbox, modules)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to start session: %v", err)), nil
+		}
+		session = started
+	}
+
+	timeout := e.sandbox.timeoutFor(request.Params.Arguments)
+	output, err := session.run(ctx, code, timeout)
+	if err != nil {
+		e.sessions.close(session.id)
+		return mcp.NewToolResultError(fmt.Sprintf("Session error: %v\noutput so far:\n%s", err, output)), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{Type: "text", Text: fmt.Sprintf("session_id: %s\n%s", session.id, output)},
+		},
+	}, nil
+}
+
+func (e *pythonExecutor) handleSessionClose(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, ok := request.Params.Arguments["session_id"].(string)
+	if !ok || id == "" {
+		return mcp.NewToolResultError("Invalid session_id parameter"), nil
+	}
+
+	if !e.sessions.close(id) {
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown session_id: %s", id)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Session %s closed", id)), nil
+}